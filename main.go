@@ -1,14 +1,18 @@
 package main
 
 import (
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"time"
 
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/metrics"
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/modbusrtu"
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/mqttpublish"
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/registermap"
 	"github.com/tarm/serial"
 )
 
@@ -21,76 +25,23 @@ type Config struct {
 	ModbusStopBit       int    `json:"modbus_stop_bit"`
 	ReadIntervalSeconds int    `json:"read_interval_seconds"`
 	ReadTimeoutMs       int    `json:"read_timeout_ms"` // Add a read timeout field
-}
-
-// Function to calculate CRC-16 for Modbus RTU
-func crc16(data []byte) uint16 {
-	var crc uint16 = 0xFFFF
-	for _, b := range data {
-		crc ^= uint16(b)
-		for i := 0; i < 8; i++ {
-			if (crc & 0x0001) != 0 {
-				crc = (crc >> 1) ^ 0xA001
-			} else {
-				crc >>= 1
-			}
-		}
-	}
-	return crc
-}
-
-// Function to build and send Modbus RTU request
-func sendModbusRequest(port *serial.Port, address byte, functionCode byte, startAddress uint16, numRegisters uint16) ([]byte, error) {
-	// Build request frame
-	request := []byte{
-		address,                   // Slave address
-		functionCode,              // Function code
-		byte(startAddress >> 8),   // Start address high byte
-		byte(startAddress & 0xFF), // Start address low byte
-		byte(numRegisters >> 8),   // Number of registers high byte
-		byte(numRegisters & 0xFF), // Number of registers low byte
-	}
-
-	crc := crc16(request)
-	request = append(request, byte(crc&0xFF))
-	request = append(request, byte(crc>>8))
-
-	// Send request
-	_, err := port.Write(request)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read response
-	response := make([]byte, 256)
-	n, err := port.Read(response)
-	if err != nil {
-		return nil, err
-	}
 
-	return response[:n], nil
-}
+	RegisterMapFile     string `json:"register_map_file"`      // defaults to registers.json
+	MaxRegistersPerRead int    `json:"max_registers_per_read"` // defaults to 125 (the RTU protocol limit)
 
-// Function to parse the Modbus RTU response
-func parseModbusResponse(response []byte, numRegisters int) ([]uint16, error) {
-	if len(response) < 3+2*numRegisters {
-		return nil, fmt.Errorf("invalid response length")
-	}
+	ProxyListen string `json:"proxy_listen"` // e.g. ":5020"; empty disables the Modbus TCP proxy
 
-	// Validate CRC
-	crc := crc16(response[:len(response)-2])
-	if crc != binary.LittleEndian.Uint16(response[len(response)-2:]) {
-		return nil, fmt.Errorf("CRC check failed")
-	}
+	MetricsListenAddr string `json:"metrics_listen_addr"` // e.g. ":9110"; empty disables the Prometheus exporter
 
-	// Extract data
-	data := response[3:] // Skip address and function code
-	result := make([]uint16, numRegisters)
-	for i := 0; i < numRegisters; i++ {
-		result[i] = binary.BigEndian.Uint16(data[i*2 : (i+1)*2])
-	}
+	MQTTBroker          string `json:"mqtt_broker"`            // e.g. "tcp://localhost:1883"; empty disables MQTT publishing
+	MQTTClientID        string `json:"mqtt_client_id"`
+	MQTTBaseTopic       string `json:"mqtt_base_topic"`        // e.g. "helios/modbus"
+	MQTTEnableDiscovery bool   `json:"mqtt_enable_discovery"` // publish Home Assistant MQTT-Discovery config topics on startup
 
-	return result, nil
+	RetryMaxAttempts    int `json:"retry_max_attempts"`     // defaults to 3
+	RetryInitialBackoff int `json:"retry_initial_backoff_ms"` // defaults to 50ms
+	RetryMaxBackoff     int `json:"retry_max_backoff_ms"`     // defaults to 1000ms
+	RetryReopenAfter    int `json:"retry_reopen_after"`       // defaults to 3 consecutive failed transactions
 }
 
 func main() {
@@ -109,6 +60,18 @@ func main() {
 	var config Config
 	json.Unmarshal(byteValue, &config)
 
+	registerMapFile := config.RegisterMapFile
+	if registerMapFile == "" {
+		registerMapFile = "registers.json"
+	}
+
+	registers, err := registermap.LoadFile(registerMapFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	batches := registermap.BuildBatches(registers, uint16(config.MaxRegistersPerRead))
+
 	// Open serial port
 	serialConfig := &serial.Config{
 		Name:        config.ModbusPort,
@@ -137,69 +100,105 @@ func main() {
 		log.Fatalf("Invalid stop bit: %d", config.ModbusStopBit)
 	}
 
-	port, err := serial.OpenPort(serialConfig)
+	retry := modbusrtu.DefaultRetryPolicy()
+	if config.RetryMaxAttempts > 0 {
+		retry.MaxAttempts = config.RetryMaxAttempts
+	}
+	if config.RetryInitialBackoff > 0 {
+		retry.InitialBackoff = time.Millisecond * time.Duration(config.RetryInitialBackoff)
+	}
+	if config.RetryMaxBackoff > 0 {
+		retry.MaxBackoff = time.Millisecond * time.Duration(config.RetryMaxBackoff)
+	}
+	if config.RetryReopenAfter > 0 {
+		retry.ReopenAfter = config.RetryReopenAfter
+	}
+
+	client, err := modbusrtu.NewClient(serialConfig, config.ModbusSlaveAddress, time.Millisecond*time.Duration(config.ReadTimeoutMs), retry)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer port.Close()
-
-	ticker := time.NewTicker(time.Duration(config.ReadIntervalSeconds) * time.Second)
-	defer ticker.Stop()
-
-	// Run the periodic read in a separate Goroutine
-	go func() {
-		for range ticker.C {
-			// Read FAN_SPEED from address 4353
-			fanSpeedResponse, err := sendModbusRequest(port, config.ModbusSlaveAddress, 0x03, 4353, 1)
-			if err != nil {
-				log.Printf("Error reading FAN_SPEED: %v", err)
-				continue
+	defer client.Close()
+
+	registry := metrics.NewRegistry(config.ModbusSlaveAddress)
+	client.OnStatusChange(func(status modbusrtu.Status) {
+		log.Printf("Modbus bus status: %s", status)
+		registry.SetBusStatus(int(status))
+	})
+
+	if config.ProxyListen != "" {
+		proxy := modbusrtu.NewProxy(client, config.ModbusSlaveAddress)
+		go func() {
+			if err := proxy.ListenAndServe(config.ProxyListen); err != nil {
+				log.Printf("Modbus TCP proxy stopped: %v", err)
 			}
+		}()
+	}
 
-			fanSpeed, err := parseModbusResponse(fanSpeedResponse, 1)
-			if err != nil {
-				log.Printf("Error parsing FAN_SPEED response: %v", err)
-				continue
+	if config.MetricsListenAddr != "" {
+		go func() {
+			if err := metrics.ListenAndServe(config.MetricsListenAddr); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
 			}
+		}()
+	}
 
-			fmt.Printf("FAN_SPEED: %d\n", fanSpeed[0])
-
-			// Read Multisensor_temp from address 4363 (12-bit value)
-			tempResponse, err := sendModbusRequest(port, config.ModbusSlaveAddress, 0x03, 4363, 1)
-			if err != nil {
-				log.Printf("Error reading Multisensor_temp: %v", err)
-				continue
-			}
+	var mqttPublisher *mqttpublish.Publisher
+	if config.MQTTBroker != "" {
+		mqttPublisher, err = mqttpublish.NewPublisher(config.MQTTBroker, config.MQTTClientID, config.MQTTBaseTopic)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer mqttPublisher.Close()
 
-			tempData, err := parseModbusResponse(tempResponse, 1)
-			if err != nil {
-				log.Printf("Error parsing Multisensor_temp response: %v", err)
-				continue
+		if config.MQTTEnableDiscovery {
+			if err := mqttPublisher.PublishDiscovery(registers); err != nil {
+				log.Printf("Error publishing MQTT discovery config: %v", err)
 			}
+		}
+	}
 
-			tempValue := tempData[0] & 0x0FFF // Mask to 12 bits
-			fmt.Printf("Multisensor_temp: %d\n", tempValue)
+	ticker := time.NewTicker(time.Duration(config.ReadIntervalSeconds) * time.Second)
+	defer ticker.Stop()
 
-			// Read state from address 4609 (0 or 1)
-			stateResponse, err := sendModbusRequest(port, config.ModbusSlaveAddress, 0x03, 4609, 1)
-			if err != nil {
-				log.Printf("Error reading state: %v", err)
-				continue
+	// Run the periodic read in a separate Goroutine
+	go func() {
+		for range ticker.C {
+			values := make(map[string]interface{})
+
+			for _, batch := range batches {
+				registry.ReadAttempts.Inc()
+
+				words, err := client.ReadHoldingRegisters(batch.StartAddress, batch.Quantity)
+				if err != nil {
+					log.Printf("Error reading registers %d..%d: %v", batch.StartAddress, batch.StartAddress+batch.Quantity-1, err)
+					_, isException := err.(*modbusrtu.ModbusError)
+					registry.ObserveError(isException, errors.Is(err, modbusrtu.ErrTimeout), errors.Is(err, modbusrtu.ErrCRC))
+					continue
+				}
+
+				for _, reg := range batch.Registers {
+					value, err := registermap.Decode(reg, batch.StartAddress, words)
+					if err != nil {
+						log.Printf("Error decoding %s: %v", reg.Name, err)
+						continue
+					}
+					values[reg.Name] = value
+					registry.Observe(reg, value)
+				}
 			}
 
-			stateData, err := parseModbusResponse(stateResponse, 1)
-			if err != nil {
-				log.Printf("Error parsing state response: %v", err)
-				continue
+			for _, reg := range registers {
+				if value, ok := values[reg.Name]; ok {
+					fmt.Printf("%s: %v\n", reg.Name, value)
+				}
 			}
 
-			stateValue := stateData[0] & 0x01 // Mask to 1 bit
-			state := "home"
-			if stateValue == 1 {
-				state = "away"
+			if mqttPublisher != nil {
+				if err := mqttPublisher.Publish(values); err != nil {
+					log.Printf("Error publishing to MQTT: %v", err)
+				}
 			}
-
-			fmt.Printf("State: %s\n", state)
 		}
 	}()
 
@@ -0,0 +1,135 @@
+// Package metrics exposes polled Modbus register values and bus-health
+// counters as Prometheus gauges/counters over HTTP, so operators can
+// alert on stale reads or a noisy bus instead of only watching stdout.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/registermap"
+)
+
+// Registry holds the Prometheus collectors for one slave device.
+type Registry struct {
+	slave string
+
+	values *prometheus.GaugeVec
+	states *prometheus.GaugeVec
+	busUp  prometheus.Gauge
+
+	ReadAttempts prometheus.Counter
+	CRCFailures  prometheus.Counter
+	Exceptions   prometheus.Counter
+	Timeouts     prometheus.Counter
+	OtherErrors  prometheus.Counter
+}
+
+// NewRegistry creates and registers the collectors for slave.
+func NewRegistry(slave byte) *Registry {
+	slaveLabel := fmt.Sprintf("%d", slave)
+
+	return &Registry{
+		slave: slaveLabel,
+
+		values: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "modbus_register_value",
+			Help: "Last decoded numeric value of a polled register.",
+		}, []string{"name", "slave"}),
+
+		states: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "modbus_register_state",
+			Help: "1 for the currently active enum/string value of a polled register, 0 for the rest.",
+		}, []string{"name", "slave", "state"}),
+
+		busUp: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:        "modbus_bus_status",
+			Help:        "Bus health: 0=healthy, 1=degraded, 2=disconnected.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+
+		ReadAttempts: promauto.NewCounter(prometheus.CounterOpts{
+			Name:        "modbus_read_attempts_total",
+			Help:        "Total number of Modbus read transactions attempted.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+		CRCFailures: promauto.NewCounter(prometheus.CounterOpts{
+			Name:        "modbus_crc_failures_total",
+			Help:        "Total number of responses rejected for a bad CRC.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+		Exceptions: promauto.NewCounter(prometheus.CounterOpts{
+			Name:        "modbus_exceptions_total",
+			Help:        "Total number of Modbus exception responses received.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+		Timeouts: promauto.NewCounter(prometheus.CounterOpts{
+			Name:        "modbus_timeouts_total",
+			Help:        "Total number of transactions that timed out waiting for a response.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+		OtherErrors: promauto.NewCounter(prometheus.CounterOpts{
+			Name:        "modbus_other_errors_total",
+			Help:        "Total number of transaction failures that were neither an exception, a timeout, nor a CRC failure.",
+			ConstLabels: prometheus.Labels{"slave": slaveLabel},
+		}),
+	}
+}
+
+// Observe records the latest decoded value of reg. Numeric values (int64,
+// float64) update the modbus_register_value gauge; any other value (an
+// enum label, say) is tracked as a one-hot modbus_register_state series
+// instead, since Prometheus gauges can't hold strings directly. For an
+// enum register, every other label in reg.Enum is explicitly set back to
+// 0 so the newly active label is the only one left at 1.
+func (r *Registry) Observe(reg registermap.Register, value interface{}) {
+	switch v := value.(type) {
+	case int64:
+		r.values.WithLabelValues(reg.Name, r.slave).Set(float64(v))
+	case float64:
+		r.values.WithLabelValues(reg.Name, r.slave).Set(v)
+	case string:
+		for _, label := range reg.Enum {
+			if label == v {
+				continue
+			}
+			r.states.WithLabelValues(reg.Name, r.slave, label).Set(0)
+		}
+		r.states.WithLabelValues(reg.Name, r.slave, v).Set(1)
+	}
+}
+
+// ObserveError increments the counter matching the kind of transaction
+// failure: isException for a Modbus exception response, isTimeout for a
+// response that never completed, isCRC for a response that failed its CRC
+// check, or OtherErrors for anything else (e.g. a write error or a
+// malformed response).
+func (r *Registry) ObserveError(isException, isTimeout, isCRC bool) {
+	switch {
+	case isException:
+		r.Exceptions.Inc()
+	case isTimeout:
+		r.Timeouts.Inc()
+	case isCRC:
+		r.CRCFailures.Inc()
+	default:
+		r.OtherErrors.Inc()
+	}
+}
+
+// SetBusStatus records the client's current bus health as
+// modbus_bus_status (0=healthy, 1=degraded, 2=disconnected).
+func (r *Registry) SetBusStatus(status int) {
+	r.busUp.Set(float64(status))
+}
+
+// ListenAndServe serves /metrics on addr until the listener fails.
+func ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,341 @@
+// Package modbusrtu implements a small Modbus RTU client over a serial
+// port. It covers the read and write function codes needed to drive a
+// Helios/Enervent ventilation unit (or any other Modbus RTU slave) without
+// hardcoding a single register layout into the caller.
+package modbusrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// MaxRTUFrameSize is the largest frame allowed on the wire by the Modbus
+// RTU spec: address + function code + PDU + 2-byte CRC.
+const MaxRTUFrameSize = 256
+
+// Function codes supported by Client.
+const (
+	FuncReadCoils              byte = 0x01
+	FuncReadDiscreteInputs     byte = 0x02
+	FuncReadHoldingRegisters   byte = 0x03
+	FuncReadInputRegisters     byte = 0x04
+	FuncWriteSingleCoil        byte = 0x05
+	FuncWriteSingleRegister    byte = 0x06
+	FuncWriteMultipleCoils     byte = 0x0F
+	FuncWriteMultipleRegisters byte = 0x10
+)
+
+// exceptionBit is set in the function code echoed back by a slave when it
+// is reporting a Modbus exception instead of a normal response.
+const exceptionBit = 0x80
+
+// ModbusError is returned when a slave replies with a Modbus exception
+// response (the function code comes back with the high bit set, followed
+// by a single exception code byte) rather than failing the transport.
+type ModbusError struct {
+	FunctionCode  byte
+	ExceptionCode byte
+}
+
+func (e *ModbusError) Error() string {
+	return fmt.Sprintf("modbus exception: function 0x%02X, code 0x%02X (%s)",
+		e.FunctionCode, e.ExceptionCode, exceptionText(e.ExceptionCode))
+}
+
+func exceptionText(code byte) string {
+	switch code {
+	case 0x01:
+		return "illegal function"
+	case 0x02:
+		return "illegal data address"
+	case 0x03:
+		return "illegal data value"
+	case 0x04:
+		return "slave device failure"
+	case 0x05:
+		return "acknowledge"
+	case 0x06:
+		return "slave device busy"
+	case 0x08:
+		return "memory parity error"
+	case 0x0A:
+		return "gateway path unavailable"
+	case 0x0B:
+		return "gateway target device failed to respond"
+	default:
+		return "unknown exception"
+	}
+}
+
+// Client is a Modbus RTU client bound to a single serial port and slave
+// address. A Client is safe for concurrent use: every transaction holds
+// mu for its full write/read round trip, so e.g. a periodic poller and a
+// Proxy can share the same serial port without colliding on the wire.
+type Client struct {
+	port         *serial.Port
+	serialConfig *serial.Config // kept so a wedged port can be closed and reopened
+	address      byte
+	timeout      time.Duration // overall time allowed to receive a full response
+	retry        RetryPolicy
+
+	mu                  sync.Mutex
+	lastTxEnd           time.Time // end of the previous transaction, for inter-frame silence
+	consecutiveFailures int       // transactions in a row that exhausted all retries; resets on success
+
+	statusMu       sync.Mutex
+	status         Status
+	onStatusChange func(Status)
+}
+
+// NewClient opens serialConfig and returns a Client for Modbus RTU
+// requests against the given slave address. serialConfig is retained so
+// the Client can reopen the port if the bus looks wedged; timeout bounds
+// how long a transaction waits for a complete response, and retry
+// controls how transient failures are retried before that happens.
+func NewClient(serialConfig *serial.Config, address byte, timeout time.Duration, retry RetryPolicy) (*Client, error) {
+	port, err := serial.OpenPort(serialConfig)
+	if err != nil {
+		return nil, fmt.Errorf("modbusrtu: open port: %w", err)
+	}
+
+	return &Client{
+		port:         port,
+		serialConfig: serialConfig,
+		address:      address,
+		timeout:      timeout,
+		retry:        retry,
+	}, nil
+}
+
+// Close closes the underlying serial port.
+func (c *Client) Close() error {
+	return c.port.Close()
+}
+
+// crc16 calculates the Modbus CRC-16 checksum for data.
+func crc16(data []byte) uint16 {
+	var crc uint16 = 0xFFFF
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if (crc & 0x0001) != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// buildFrame assembles address + functionCode + pdu and appends the CRC.
+func buildFrame(address, functionCode byte, pdu []byte) []byte {
+	frame := make([]byte, 0, 2+len(pdu)+2)
+	frame = append(frame, address, functionCode)
+	frame = append(frame, pdu...)
+	crc := crc16(frame)
+	frame = append(frame, byte(crc&0xFF), byte(crc>>8))
+	return frame
+}
+
+// doTransaction runs a transaction against the client's own slave address,
+// using qty to predict the response length for the read function codes
+// (ignored for writes).
+func (c *Client) doTransaction(functionCode byte, pdu []byte, qty uint16) ([]byte, error) {
+	return c.transact(c.address, functionCode, pdu, qty)
+}
+
+// RawTransaction sends an already-encoded PDU (function code followed by
+// its data) to address and returns the response PDU, without requiring
+// the caller to go through one of Client's typed methods. This is used by
+// Proxy to forward requests from Modbus TCP clients onto the RTU bus for
+// an address that may differ from the client's own.
+func (c *Client) RawTransaction(address byte, pdu []byte) ([]byte, error) {
+	if len(pdu) == 0 {
+		return nil, fmt.Errorf("modbusrtu: empty PDU")
+	}
+	functionCode := pdu[0]
+
+	qty := uint16(0)
+	if len(pdu) >= 5 {
+		switch functionCode {
+		case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+			qty = binary.BigEndian.Uint16(pdu[3:5])
+		}
+	}
+
+	resp, err := c.transact(address, functionCode, pdu[1:], qty)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{functionCode}, resp...), nil
+}
+
+// rawTransact sends a single request built from address, functionCode and
+// pdu, reads the response, validates its CRC, and returns the response
+// PDU (with address, function code and CRC stripped). A Modbus exception
+// response is surfaced as a *ModbusError. The caller must hold c.mu.
+func (c *Client) rawTransact(address, functionCode byte, pdu []byte, qty uint16) ([]byte, error) {
+	c.waitForSilence()
+
+	request := buildFrame(address, functionCode, pdu)
+	if err := checkFrameSize(len(request)); err != nil {
+		return nil, err
+	}
+	if _, err := c.port.Write(request); err != nil {
+		c.lastTxEnd = time.Now()
+		return nil, fmt.Errorf("modbusrtu: write request: %w", err)
+	}
+
+	response, err := c.readResponse(functionCode, qty)
+	c.lastTxEnd = time.Now()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyCRC(response); err != nil {
+		return nil, err
+	}
+
+	respFunctionCode := response[1]
+	if respFunctionCode&exceptionBit != 0 {
+		return nil, &ModbusError{
+			FunctionCode:  respFunctionCode &^ exceptionBit,
+			ExceptionCode: response[2],
+		}
+	}
+
+	return response[2 : len(response)-2], nil
+}
+
+// ReadHoldingRegisters reads qty holding registers starting at addr
+// (function code 0x03).
+func (c *Client) ReadHoldingRegisters(addr, qty uint16) ([]uint16, error) {
+	return c.readRegisters(FuncReadHoldingRegisters, addr, qty)
+}
+
+// ReadInputRegisters reads qty input registers starting at addr (function
+// code 0x04).
+func (c *Client) ReadInputRegisters(addr, qty uint16) ([]uint16, error) {
+	return c.readRegisters(FuncReadInputRegisters, addr, qty)
+}
+
+func (c *Client) readRegisters(functionCode byte, addr, qty uint16) ([]uint16, error) {
+	pdu := []byte{byte(addr >> 8), byte(addr & 0xFF), byte(qty >> 8), byte(qty & 0xFF)}
+
+	resp, err := c.doTransaction(functionCode, pdu, qty)
+	if err != nil {
+		return nil, err
+	}
+
+	byteCount := int(resp[0])
+	data := resp[1:]
+	if len(data) < byteCount || byteCount != 2*int(qty) {
+		return nil, fmt.Errorf("modbusrtu: unexpected byte count %d for %d registers", byteCount, qty)
+	}
+
+	result := make([]uint16, qty)
+	for i := range result {
+		result[i] = binary.BigEndian.Uint16(data[i*2 : i*2+2])
+	}
+	return result, nil
+}
+
+// ReadCoils reads qty coils starting at addr (function code 0x01),
+// unpacking the packed-bit response into one bool per coil.
+func (c *Client) ReadCoils(addr, qty uint16) ([]bool, error) {
+	return c.readBits(FuncReadCoils, addr, qty)
+}
+
+// ReadDiscreteInputs reads qty discrete inputs starting at addr (function
+// code 0x02), unpacking the packed-bit response into one bool per input.
+func (c *Client) ReadDiscreteInputs(addr, qty uint16) ([]bool, error) {
+	return c.readBits(FuncReadDiscreteInputs, addr, qty)
+}
+
+func (c *Client) readBits(functionCode byte, addr, qty uint16) ([]bool, error) {
+	pdu := []byte{byte(addr >> 8), byte(addr & 0xFF), byte(qty >> 8), byte(qty & 0xFF)}
+
+	resp, err := c.doTransaction(functionCode, pdu, qty)
+	if err != nil {
+		return nil, err
+	}
+
+	byteCount := int(resp[0])
+	data := resp[1:]
+	expectedBytes := (int(qty) + 7) / 8
+	if len(data) < byteCount || byteCount != expectedBytes {
+		return nil, fmt.Errorf("modbusrtu: unexpected byte count %d for %d bits", byteCount, qty)
+	}
+
+	return unpackBits(data, int(qty)), nil
+}
+
+// unpackBits expands packed little-endian bits (as returned for coils and
+// discrete inputs) into one bool per requested bit.
+func unpackBits(data []byte, qty int) []bool {
+	result := make([]bool, qty)
+	for i := 0; i < qty; i++ {
+		result[i] = data[i/8]&(1<<uint(i%8)) != 0
+	}
+	return result
+}
+
+// WriteSingleCoil writes a single coil at addr (function code 0x05).
+func (c *Client) WriteSingleCoil(addr uint16, value bool) error {
+	coilValue := uint16(0x0000)
+	if value {
+		coilValue = 0xFF00
+	}
+	pdu := []byte{byte(addr >> 8), byte(addr & 0xFF), byte(coilValue >> 8), byte(coilValue & 0xFF)}
+	_, err := c.doTransaction(FuncWriteSingleCoil, pdu, 0)
+	return err
+}
+
+// WriteSingleRegister writes a single holding register at addr (function
+// code 0x06).
+func (c *Client) WriteSingleRegister(addr, value uint16) error {
+	pdu := []byte{byte(addr >> 8), byte(addr & 0xFF), byte(value >> 8), byte(value & 0xFF)}
+	_, err := c.doTransaction(FuncWriteSingleRegister, pdu, 0)
+	return err
+}
+
+// WriteMultipleCoils writes values to qty consecutive coils starting at
+// addr (function code 0x0F).
+func (c *Client) WriteMultipleCoils(addr uint16, values []bool) error {
+	qty := uint16(len(values))
+	byteCount := (len(values) + 7) / 8
+	packed := make([]byte, byteCount)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	pdu := make([]byte, 0, 5+byteCount)
+	pdu = append(pdu, byte(addr>>8), byte(addr&0xFF), byte(qty>>8), byte(qty&0xFF), byte(byteCount))
+	pdu = append(pdu, packed...)
+
+	_, err := c.doTransaction(FuncWriteMultipleCoils, pdu, 0)
+	return err
+}
+
+// WriteMultipleRegisters writes values to consecutive holding registers
+// starting at addr (function code 0x10).
+func (c *Client) WriteMultipleRegisters(addr uint16, values []uint16) error {
+	qty := uint16(len(values))
+	byteCount := 2 * len(values)
+
+	pdu := make([]byte, 0, 5+byteCount)
+	pdu = append(pdu, byte(addr>>8), byte(addr&0xFF), byte(qty>>8), byte(qty&0xFF), byte(byteCount))
+	for _, v := range values {
+		pdu = append(pdu, byte(v>>8), byte(v&0xFF))
+	}
+
+	_, err := c.doTransaction(FuncWriteMultipleRegisters, pdu, 0)
+	return err
+}
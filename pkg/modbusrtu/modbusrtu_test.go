@@ -0,0 +1,81 @@
+package modbusrtu
+
+import "testing"
+
+func TestCRC16Deterministic(t *testing.T) {
+	frame := []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03}
+	if crc16(frame) != crc16(frame) {
+		t.Fatal("crc16 is not deterministic for identical input")
+	}
+	if crc16(frame) == crc16([]byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x04}) {
+		t.Fatal("crc16 did not change when the frame content changed")
+	}
+}
+
+func TestBuildFrameCRCRoundTrips(t *testing.T) {
+	pdu := []byte{0x00, 0x6B, 0x00, 0x03}
+	frame := buildFrame(0x11, FuncReadHoldingRegisters, pdu)
+
+	if len(frame) != len(pdu)+4 {
+		t.Fatalf("buildFrame length = %d, want %d", len(frame), len(pdu)+4)
+	}
+	if err := verifyCRC(frame); err != nil {
+		t.Errorf("verifyCRC(buildFrame(...)) = %v, want nil", err)
+	}
+
+	frame[0] ^= 0xFF
+	if err := verifyCRC(frame); err == nil {
+		t.Error("verifyCRC accepted a frame with a corrupted byte")
+	}
+}
+
+func TestModbusErrorText(t *testing.T) {
+	cases := []struct {
+		code byte
+		want string
+	}{
+		{0x01, "illegal function"},
+		{0x02, "illegal data address"},
+		{0x03, "illegal data value"},
+		{0x04, "slave device failure"},
+		{0xFF, "unknown exception"},
+	}
+	for _, c := range cases {
+		err := &ModbusError{FunctionCode: FuncReadHoldingRegisters, ExceptionCode: c.code}
+		if got := err.Error(); got != "modbus exception: function 0x03, code "+hexByte(c.code)+" ("+c.want+")" {
+			t.Errorf("ModbusError{code %#02x}.Error() = %q, want exception text %q", c.code, got, c.want)
+		}
+	}
+}
+
+func hexByte(b byte) string {
+	const hex = "0123456789ABCDEF"
+	return string([]byte{'0', 'x', hex[b>>4], hex[b&0xF]})
+}
+
+func TestUnpackBits(t *testing.T) {
+	// 0b00000101 -> bits 0 and 2 set, little-endian within the byte.
+	data := []byte{0x05}
+	got := unpackBits(data, 4)
+	want := []bool{true, false, true, false}
+	if len(got) != len(want) {
+		t.Fatalf("unpackBits length = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unpackBits[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUnpackBitsAcrossBytes(t *testing.T) {
+	// bit 9 (second byte, bit 1) should unpack true; everything else false.
+	data := []byte{0x00, 0x02}
+	got := unpackBits(data, 10)
+	for i, v := range got {
+		want := i == 9
+		if v != want {
+			t.Errorf("unpackBits[%d] = %v, want %v", i, v, want)
+		}
+	}
+}
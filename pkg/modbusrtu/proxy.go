@@ -0,0 +1,122 @@
+package modbusrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+)
+
+// mbapHeaderLength is the size of the Modbus TCP (MBAP) header: 2-byte
+// transaction ID, 2-byte protocol ID (always 0), 2-byte length and 1-byte
+// unit ID.
+const mbapHeaderLength = 7
+
+// RawTransactor is the subset of Client that Proxy forwards requests
+// through. It's satisfied by *Client; tests can supply a fake backend
+// instead of a real serial port.
+type RawTransactor interface {
+	RawTransaction(address byte, pdu []byte) ([]byte, error)
+}
+
+// Proxy accepts Modbus TCP (MBAP) connections and forwards each request
+// onto client's RTU serial bus, letting tools like modpoll or Home
+// Assistant talk to the device without taking the bus away from whatever
+// else is using client (a periodic poller, say). Requests are serialized
+// through Client's own mutex, so no extra locking is needed here.
+type Proxy struct {
+	client       RawTransactor
+	defaultSlave byte // used when the incoming MBAP unit ID is 0 (broadcast placeholder)
+}
+
+// NewProxy returns a Proxy that forwards onto client, substituting
+// defaultSlave for requests whose MBAP unit ID is 0.
+func NewProxy(client RawTransactor, defaultSlave byte) *Proxy {
+	return &Proxy{client: client, defaultSlave: defaultSlave}
+}
+
+// ListenAndServe listens on addr and serves Modbus TCP clients until the
+// listener is closed or accepting fails.
+func (p *Proxy) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("modbusrtu: proxy listen: %w", err)
+	}
+	defer ln.Close()
+
+	log.Printf("modbusrtu: proxy listening on %s", addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return fmt.Errorf("modbusrtu: proxy accept: %w", err)
+		}
+		go p.handleConn(conn)
+	}
+}
+
+func (p *Proxy) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		header := make([]byte, mbapHeaderLength)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if err != io.EOF {
+				log.Printf("modbusrtu: proxy read MBAP header from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		transactionID := binary.BigEndian.Uint16(header[0:2])
+		length := binary.BigEndian.Uint16(header[4:6])
+		unitID := header[6]
+
+		if length == 0 {
+			log.Printf("modbusrtu: proxy got zero-length MBAP frame from %s", conn.RemoteAddr())
+			return
+		}
+
+		pdu := make([]byte, int(length)-1)
+		if len(pdu) > 0 {
+			if _, err := io.ReadFull(conn, pdu); err != nil {
+				log.Printf("modbusrtu: proxy read PDU from %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+		}
+
+		slave := unitID
+		if slave == 0 {
+			slave = p.defaultSlave
+		}
+
+		respPDU, err := p.client.RawTransaction(slave, pdu)
+		if err != nil {
+			modbusErr, ok := err.(*ModbusError)
+			if !ok {
+				log.Printf("modbusrtu: proxy transaction for %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			respPDU = []byte{modbusErr.FunctionCode | exceptionBit, modbusErr.ExceptionCode}
+		}
+
+		if err := writeMBAPResponse(conn, transactionID, unitID, respPDU); err != nil {
+			log.Printf("modbusrtu: proxy write response to %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// writeMBAPResponse wraps respPDU in an MBAP header carrying the
+// original transactionID and unitID and writes it to conn.
+func writeMBAPResponse(conn net.Conn, transactionID uint16, unitID byte, respPDU []byte) error {
+	frame := make([]byte, mbapHeaderLength+len(respPDU))
+	binary.BigEndian.PutUint16(frame[0:2], transactionID)
+	binary.BigEndian.PutUint16(frame[2:4], 0) // protocol ID
+	binary.BigEndian.PutUint16(frame[4:6], uint16(1+len(respPDU)))
+	frame[6] = unitID
+	copy(frame[7:], respPDU)
+
+	_, err := conn.Write(frame)
+	return err
+}
@@ -0,0 +1,158 @@
+package modbusrtu
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeTransactor is a RawTransactor that echoes back a fixed response (or
+// a ModbusError) without touching a real serial port.
+type fakeTransactor struct {
+	gotAddress byte
+	gotPDU     []byte
+
+	respPDU []byte
+	err     error
+}
+
+func (f *fakeTransactor) RawTransaction(address byte, pdu []byte) ([]byte, error) {
+	f.gotAddress = address
+	f.gotPDU = append([]byte(nil), pdu...)
+	return f.respPDU, f.err
+}
+
+func TestProxyRoundTrip(t *testing.T) {
+	backend := &fakeTransactor{respPDU: []byte{0x03, 0x02, 0x00, 0x2A}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	proxy := NewProxy(backend, 0x11)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		proxy.handleConn(conn)
+	}()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	<-accepted
+
+	// MBAP request: transaction ID 0x0001, protocol 0, length 6, unit 0
+	// (broadcast -> should use the proxy's defaultSlave), PDU = read
+	// holding registers 0x0000 qty 1.
+	pdu := []byte{FuncReadHoldingRegisters, 0x00, 0x00, 0x00, 0x01}
+	frame := make([]byte, mbapHeaderLength+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:2], 0x0001)
+	binary.BigEndian.PutUint16(frame[2:4], 0)
+	binary.BigEndian.PutUint16(frame[4:6], uint16(1+len(pdu)))
+	frame[6] = 0x00
+	copy(frame[7:], pdu)
+
+	if _, err := client.Write(frame); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	respLen := mbapHeaderLength + len(backend.respPDU)
+	resp := make([]byte, respLen)
+	if _, err := readFullFromConn(client, resp); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+
+	if backend.gotAddress != 0x11 {
+		t.Errorf("backend saw address %#02x, want the proxy's default slave 0x11", backend.gotAddress)
+	}
+	if string(backend.gotPDU) != string(pdu) {
+		t.Errorf("backend saw PDU %X, want %X", backend.gotPDU, pdu)
+	}
+
+	gotTransactionID := binary.BigEndian.Uint16(resp[0:2])
+	if gotTransactionID != 0x0001 {
+		t.Errorf("response transaction ID = %#04x, want 0x0001", gotTransactionID)
+	}
+	gotUnitID := resp[6]
+	if gotUnitID != 0x00 {
+		t.Errorf("response unit ID = %#02x, want the original 0x00", gotUnitID)
+	}
+	if string(resp[7:]) != string(backend.respPDU) {
+		t.Errorf("response PDU = %X, want %X", resp[7:], backend.respPDU)
+	}
+}
+
+func TestProxyForwardsModbusException(t *testing.T) {
+	backend := &fakeTransactor{err: &ModbusError{FunctionCode: FuncReadHoldingRegisters, ExceptionCode: 0x02}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	proxy := NewProxy(backend, 0x01)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		proxy.handleConn(conn)
+	}()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	<-accepted
+
+	pdu := []byte{FuncReadHoldingRegisters, 0x00, 0x00, 0x00, 0x01}
+	frame := make([]byte, mbapHeaderLength+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:2], 0x0007)
+	binary.BigEndian.PutUint16(frame[4:6], uint16(1+len(pdu)))
+	frame[6] = 0x01
+	copy(frame[7:], pdu)
+
+	if _, err := client.Write(frame); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp := make([]byte, mbapHeaderLength+2)
+	if _, err := readFullFromConn(client, resp); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+
+	wantFunctionCode := FuncReadHoldingRegisters | exceptionBit
+	if resp[7] != wantFunctionCode {
+		t.Errorf("response function code = %#02x, want %#02x", resp[7], wantFunctionCode)
+	}
+	if resp[8] != 0x02 {
+		t.Errorf("response exception code = %#02x, want 0x02", resp[8])
+	}
+}
+
+func readFullFromConn(conn net.Conn, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := conn.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
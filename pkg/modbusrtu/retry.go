@@ -0,0 +1,180 @@
+package modbusrtu
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// RetryPolicy configures how a Client retries a transaction that fails
+// with a transient error (timeout, CRC failure, short read) before giving
+// up. Modbus exceptions are never retried, since the slave has already
+// answered authoritatively.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts per transaction, including the first; 1 disables retrying
+	InitialBackoff time.Duration // delay before the second attempt
+	MaxBackoff     time.Duration // cap applied when Exponential backoff keeps growing
+	Exponential    bool          // double the backoff after every failed attempt, up to MaxBackoff
+
+	// ReopenAfter is how many consecutive transactions must exhaust all
+	// their retries before the port is closed and reopened; 1 reopens
+	// after every failed transaction.
+	ReopenAfter int
+}
+
+// DefaultRetryPolicy is a reasonable starting point: three attempts with
+// exponential backoff from 50ms up to 1s, reopening the port after three
+// consecutive transactions fail outright.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Exponential:    true,
+		ReopenAfter:    3,
+	}
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) reopenAfter() int {
+	if p.ReopenAfter <= 0 {
+		return 1
+	}
+	return p.ReopenAfter
+}
+
+// Status reports the health of a Client's connection to the serial bus.
+type Status int
+
+// Bus health states, in increasing order of severity.
+const (
+	StatusHealthy Status = iota
+	StatusDegraded
+	StatusDisconnected
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusHealthy:
+		return "healthy"
+	case StatusDegraded:
+		return "degraded"
+	case StatusDisconnected:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// OnStatusChange registers a callback invoked whenever the Client's bus
+// health status changes. Only one callback can be registered at a time.
+func (c *Client) OnStatusChange(fn func(Status)) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.onStatusChange = fn
+}
+
+// Status returns the Client's current bus health status.
+func (c *Client) Status() Status {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.status
+}
+
+func (c *Client) setStatus(status Status) {
+	c.statusMu.Lock()
+	changed := c.status != status
+	c.status = status
+	fn := c.onStatusChange
+	c.statusMu.Unlock()
+
+	if changed && fn != nil {
+		fn(status)
+	}
+}
+
+// isTransient reports whether err is worth retrying: a timeout, CRC
+// failure or other transport-level problem. A *ModbusError means the
+// slave understood and rejected the request, which retrying won't fix.
+func isTransient(err error) bool {
+	_, permanent := err.(*ModbusError)
+	return !permanent
+}
+
+// transact runs rawTransact under c.mu, retrying transient failures per
+// c.retry. Once c.retry.ReopenAfter consecutive transactions have each
+// exhausted all their retries, it closes and reopens the serial port (in
+// case e.g. a USB-serial adapter re-enumerated) before giving up, and
+// reports the resulting bus health via Status/OnStatusChange.
+func (c *Client) transact(address, functionCode byte, pdu []byte, qty uint16) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	backoff := c.retry.InitialBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= c.retry.maxAttempts(); attempt++ {
+		resp, err := c.rawTransact(address, functionCode, pdu, qty)
+		if err == nil {
+			c.consecutiveFailures = 0
+			c.setStatus(StatusHealthy)
+			return resp, nil
+		}
+
+		if !isTransient(err) {
+			// The slave answered with an exception: the bus is fine, the
+			// request wasn't. Don't retry or touch the connection status.
+			return nil, err
+		}
+
+		lastErr = err
+		c.setStatus(StatusDegraded)
+
+		if attempt == c.retry.maxAttempts() {
+			break
+		}
+
+		time.Sleep(backoff)
+		if c.retry.Exponential {
+			backoff *= 2
+			if c.retry.MaxBackoff > 0 && backoff > c.retry.MaxBackoff {
+				backoff = c.retry.MaxBackoff
+			}
+		}
+	}
+
+	c.consecutiveFailures++
+	if c.consecutiveFailures < c.retry.reopenAfter() {
+		return nil, lastErr
+	}
+
+	if reopenErr := c.reopen(); reopenErr != nil {
+		c.setStatus(StatusDisconnected)
+		return nil, fmt.Errorf("modbusrtu: %w (reopening port also failed: %v)", lastErr, reopenErr)
+	}
+
+	c.consecutiveFailures = 0
+	return nil, lastErr
+}
+
+// reopen closes and reopens the serial port using the config the Client
+// was created with. The caller must hold c.mu.
+func (c *Client) reopen() error {
+	c.port.Close()
+
+	port, err := serial.OpenPort(c.serialConfig)
+	if err != nil {
+		return err
+	}
+
+	c.port = port
+	c.lastTxEnd = time.Time{}
+	return nil
+}
@@ -0,0 +1,174 @@
+package modbusrtu
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is wrapped into the error returned by a transaction that gave
+// up waiting for a complete response. Callers distinguish it with
+// errors.Is instead of matching on error text.
+var ErrTimeout = errors.New("modbusrtu: timed out waiting for response")
+
+// ErrCRC is wrapped into the error returned when a response frame's CRC
+// doesn't match its contents.
+var ErrCRC = errors.New("modbusrtu: CRC check failed")
+
+// interCharacterSilence returns the Modbus RTU t3.5 inter-frame silence
+// period for baud: 3.5 character times, where one character is 11 bits
+// (start + 8 data + parity + stop). The spec fixes this at 1.75ms for
+// baud rates of 19200 or higher, since the computed value would otherwise
+// become too short to reliably detect.
+func interCharacterSilence(baud int) time.Duration {
+	if baud >= 19200 {
+		return 1750 * time.Microsecond
+	}
+	if baud <= 0 {
+		baud = 9600
+	}
+	return time.Duration(3.5 * 11 * float64(time.Second) / float64(baud))
+}
+
+// responseLength returns the total RTU frame length (address + function
+// code + data + 2-byte CRC) expected for a non-exception response to
+// functionCode, given the register/coil quantity requested (qty is
+// ignored for the write function codes, which always reply with a fixed
+// 8-byte frame).
+func responseLength(functionCode byte, qty uint16) (int, error) {
+	switch functionCode {
+	case FuncReadHoldingRegisters, FuncReadInputRegisters:
+		return 5 + 2*int(qty), nil
+	case FuncReadCoils, FuncReadDiscreteInputs:
+		return 5 + (int(qty)+7)/8, nil
+	case FuncWriteSingleCoil, FuncWriteSingleRegister, FuncWriteMultipleCoils, FuncWriteMultipleRegisters:
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("modbusrtu: unknown function code 0x%02X", functionCode)
+	}
+}
+
+// checkFrameSize rejects frame lengths the RTU spec doesn't allow on the
+// wire, catching a miscomputed qty before it drives an oversized read.
+func checkFrameSize(length int) error {
+	if length > MaxRTUFrameSize {
+		return fmt.Errorf("modbusrtu: frame length %d exceeds max RTU frame size %d", length, MaxRTUFrameSize)
+	}
+	return nil
+}
+
+// exceptionFrameLength is the fixed length of a Modbus exception response:
+// address + function code (with the exception bit set) + exception code +
+// 2-byte CRC.
+const exceptionFrameLength = 5
+
+// readFull reads exactly len(buf) bytes from the port, issuing further
+// Read calls as needed when the response arrives in more than one chunk.
+// It gives up once overall has elapsed since the call began (0 means no
+// limit).
+func (c *Client) readFull(buf []byte, overall time.Duration) error {
+	start := time.Now()
+	read := 0
+	for read < len(buf) {
+		if overall > 0 && time.Since(start) > overall {
+			return fmt.Errorf("modbusrtu: timed out after %d/%d bytes: %w", read, len(buf), ErrTimeout)
+		}
+		n, err := c.port.Read(buf[read:])
+		if err != nil {
+			return err
+		}
+		read += n
+	}
+	return nil
+}
+
+// readResponse reads one RTU response frame for a request sent with
+// functionCode and qty (qty is only meaningful for the read function
+// codes). It first reads the 2-byte address/function-code header, then
+// — depending on whether the exception bit is set — reads the remainder
+// of either a fixed 5-byte exception frame or the frame length predicted
+// by responseLength.
+func (c *Client) readResponse(functionCode byte, qty uint16) ([]byte, error) {
+	// All reads of one response share a single c.timeout budget.
+	deadline := time.Now().Add(c.timeout)
+	remaining := func() time.Duration {
+		if c.timeout <= 0 {
+			return 0
+		}
+		if r := time.Until(deadline); r > 0 {
+			return r
+		}
+		return time.Nanosecond
+	}
+
+	header := make([]byte, 2)
+	if err := c.readFull(header, remaining()); err != nil {
+		return nil, fmt.Errorf("modbusrtu: read response header: %w", err)
+	}
+
+	if header[1]&exceptionBit != 0 {
+		rest := make([]byte, exceptionFrameLength-len(header))
+		if err := c.readFull(rest, remaining()); err != nil {
+			return nil, fmt.Errorf("modbusrtu: read exception response: %w", err)
+		}
+		return append(header, rest...), nil
+	}
+
+	total, err := responseLength(functionCode, qty)
+	if err != nil {
+		// Function code not in our known set (e.g. forwarded through Proxy
+		// for a slave we don't otherwise talk to) — fall back to reading
+		// whatever arrives in a single chunk rather than guessing a length.
+		rest, err := c.readBestEffort()
+		if err != nil {
+			return nil, fmt.Errorf("modbusrtu: read response: %w", err)
+		}
+		return append(header, rest...), nil
+	}
+	if err := checkFrameSize(total); err != nil {
+		return nil, err
+	}
+
+	rest := make([]byte, total-len(header))
+	if err := c.readFull(rest, remaining()); err != nil {
+		return nil, fmt.Errorf("modbusrtu: read response: %w", err)
+	}
+	return append(header, rest...), nil
+}
+
+// readBestEffort reads whatever the port has available in a single Read
+// call, used when the expected response length can't be predicted.
+func (c *Client) readBestEffort() ([]byte, error) {
+	buf := make([]byte, MaxRTUFrameSize)
+	n, err := c.port.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// waitForSilence blocks, if necessary, until at least one t3.5
+// inter-frame silence period has elapsed since the end of the previous
+// transaction, as required by the Modbus RTU timing spec.
+func (c *Client) waitForSilence() {
+	if c.lastTxEnd.IsZero() {
+		return
+	}
+	needed := interCharacterSilence(c.serialConfig.Baud)
+	if elapsed := time.Since(c.lastTxEnd); elapsed < needed {
+		time.Sleep(needed - elapsed)
+	}
+}
+
+// verifyCRC checks the trailing 2-byte CRC of a full response frame.
+func verifyCRC(frame []byte) error {
+	if len(frame) < 5 {
+		return fmt.Errorf("modbusrtu: response too short (%d bytes)", len(frame))
+	}
+	crc := crc16(frame[:len(frame)-2])
+	if crc != binary.LittleEndian.Uint16(frame[len(frame)-2:]) {
+		return ErrCRC
+	}
+	return nil
+}
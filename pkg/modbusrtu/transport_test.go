@@ -0,0 +1,57 @@
+package modbusrtu
+
+import "testing"
+
+func TestResponseLength(t *testing.T) {
+	cases := []struct {
+		functionCode byte
+		qty          uint16
+		want         int
+	}{
+		{FuncReadHoldingRegisters, 3, 5 + 2*3},
+		{FuncReadInputRegisters, 10, 5 + 2*10},
+		{FuncReadCoils, 10, 5 + 2}, // (10+7)/8 = 2
+		{FuncReadDiscreteInputs, 9, 5 + 2},
+		{FuncWriteSingleCoil, 0, 8},
+		{FuncWriteSingleRegister, 0, 8},
+		{FuncWriteMultipleCoils, 0, 8},
+		{FuncWriteMultipleRegisters, 0, 8},
+	}
+	for _, c := range cases {
+		got, err := responseLength(c.functionCode, c.qty)
+		if err != nil {
+			t.Errorf("responseLength(0x%02X, %d) returned error: %v", c.functionCode, c.qty, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("responseLength(0x%02X, %d) = %d, want %d", c.functionCode, c.qty, got, c.want)
+		}
+	}
+}
+
+func TestResponseLengthUnknownFunctionCode(t *testing.T) {
+	if _, err := responseLength(0x7F, 1); err == nil {
+		t.Error("responseLength with an unknown function code should fail")
+	}
+}
+
+func TestCheckFrameSize(t *testing.T) {
+	if err := checkFrameSize(MaxRTUFrameSize); err != nil {
+		t.Errorf("checkFrameSize(MaxRTUFrameSize) = %v, want nil", err)
+	}
+	if err := checkFrameSize(MaxRTUFrameSize + 1); err == nil {
+		t.Error("checkFrameSize(MaxRTUFrameSize+1) should fail")
+	}
+}
+
+func TestInterCharacterSilence(t *testing.T) {
+	if got := interCharacterSilence(19200); got <= 0 {
+		t.Errorf("interCharacterSilence(19200) = %v, want > 0", got)
+	}
+	if got := interCharacterSilence(9600); got <= interCharacterSilence(19200) {
+		t.Errorf("interCharacterSilence(9600) = %v, want > interCharacterSilence(19200)", got)
+	}
+	if got := interCharacterSilence(0); got != interCharacterSilence(9600) {
+		t.Errorf("interCharacterSilence(0) = %v, want the 9600-baud default", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package mqttpublish publishes polled register values to an MQTT broker
+// as JSON, with optional Home Assistant MQTT-Discovery config topics so
+// entities show up automatically without manual YAML.
+package mqttpublish
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/rozicdejan/Helios_ModbusRTU_readExample/pkg/registermap"
+)
+
+// Publisher posts polled values to an MQTT broker.
+type Publisher struct {
+	client    mqtt.Client
+	baseTopic string
+}
+
+// NewPublisher connects to broker (e.g. "tcp://localhost:1883") and
+// returns a Publisher that posts under baseTopic (e.g. "helios/modbus").
+func NewPublisher(broker, clientID, baseTopic string) (*Publisher, error) {
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID(clientID).SetAutoReconnect(true)
+
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("mqttpublish: connect to %s: %w", broker, token.Error())
+	}
+
+	return &Publisher{client: client, baseTopic: baseTopic}, nil
+}
+
+// Publish posts values as a single retained JSON message to
+// "<baseTopic>/state".
+func (p *Publisher) Publish(values map[string]interface{}) error {
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("mqttpublish: marshal values: %w", err)
+	}
+
+	topic := p.baseTopic + "/state"
+	token := p.client.Publish(topic, 0, true, payload)
+	token.Wait()
+	return token.Error()
+}
+
+// haDiscoveryConfig is the subset of Home Assistant's MQTT sensor
+// discovery schema this package fills in.
+type haDiscoveryConfig struct {
+	Name          string `json:"name"`
+	UniqueID      string `json:"unique_id"`
+	StateTopic    string `json:"state_topic"`
+	ValueTemplate string `json:"value_template"`
+}
+
+// PublishDiscovery publishes a retained Home Assistant MQTT-Discovery
+// config topic for each register in registers, pointing at the shared
+// state topic this Publisher posts to. Call once at startup.
+func (p *Publisher) PublishDiscovery(registers []registermap.Register) error {
+	for _, reg := range registers {
+		uniqueID := fmt.Sprintf("%s_%s", p.baseTopic, reg.Name)
+		config := haDiscoveryConfig{
+			Name:          reg.Name,
+			UniqueID:      uniqueID,
+			StateTopic:    p.baseTopic + "/state",
+			ValueTemplate: fmt.Sprintf("{{ value_json.%s }}", reg.Name),
+		}
+
+		payload, err := json.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("mqttpublish: marshal discovery config for %s: %w", reg.Name, err)
+		}
+
+		topic := fmt.Sprintf("homeassistant/sensor/%s/config", uniqueID)
+		token := p.client.Publish(topic, 0, true, payload)
+		if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+			return fmt.Errorf("mqttpublish: publish discovery config for %s: %w", reg.Name, token.Error())
+		}
+	}
+	return nil
+}
+
+// Close disconnects from the broker.
+func (p *Publisher) Close() {
+	p.client.Disconnect(250)
+}
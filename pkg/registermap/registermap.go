@@ -0,0 +1,188 @@
+// Package registermap loads a user-editable description of a Modbus
+// device's register layout (name, address, data type, scaling) and
+// decodes raw register words according to it. This lets the caller drive
+// an arbitrary device from a config file instead of hardcoding register
+// addresses and types.
+package registermap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Type identifies how a register's raw words are decoded into a value.
+type Type string
+
+// Supported register types.
+const (
+	TypeWord        Type = "WORD"         // unsigned 16-bit
+	TypeSWord       Type = "SWORD"        // signed 16-bit
+	TypeUint32      Type = "UINT32"       // unsigned 32-bit, big-endian word order
+	TypeFloat32ABCD Type = "FLOAT32_ABCD" // IEEE754 float, big-endian word order
+	TypeFloat32CDAB Type = "FLOAT32_CDAB" // IEEE754 float, swapped word order
+	TypeBit         Type = "BIT"          // single bit masked out of a WORD
+)
+
+// Register describes a single named value in a device's register map.
+type Register struct {
+	Name     string            `json:"name"`
+	Address  uint16            `json:"address"`
+	Type     Type              `json:"type"`
+	Length   uint16            `json:"length"`   // number of 16-bit registers this value occupies; defaults to 1 (2 for UINT32/FLOAT32)
+	Scale    float64           `json:"scale"`    // applied as (raw*Scale)+Offset; 0 is treated as 1 (no scaling)
+	Offset   float64           `json:"offset"`
+	BitMask  uint16            `json:"bit_mask"`  // for BIT type, mask applied before shifting
+	BitShift uint              `json:"bit_shift"` // for BIT type, right shift applied after masking
+	Enum     map[string]string `json:"enum"`      // maps the decoded integer value (as a string) to a label
+}
+
+// wordLength returns how many consecutive 16-bit registers this register
+// occupies, applying the type's natural default when Length is unset.
+func (r Register) wordLength() uint16 {
+	if r.Length != 0 {
+		return r.Length
+	}
+	switch r.Type {
+	case TypeUint32, TypeFloat32ABCD, TypeFloat32CDAB:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// scale returns r.Scale, treating the zero value as "no scaling" so a map
+// entry doesn't need to spell out "scale": 1 for every unscaled register.
+func (r Register) scale() float64 {
+	if r.Scale == 0 {
+		return 1
+	}
+	return r.Scale
+}
+
+// LoadFile reads a register map from a JSON file, e.g. registers.json.
+func LoadFile(path string) ([]Register, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("registermap: read %s: %w", path, err)
+	}
+
+	var registers []Register
+	if err := json.Unmarshal(data, &registers); err != nil {
+		return nil, fmt.Errorf("registermap: parse %s: %w", path, err)
+	}
+
+	return registers, nil
+}
+
+// Batch is a single contiguous read covering one or more registers from
+// the map.
+type Batch struct {
+	StartAddress uint16
+	Quantity     uint16
+	Registers    []Register
+}
+
+// BuildBatches groups registers with adjacent (or overlapping) addresses
+// into the fewest possible reads, each no larger than maxRegistersPerRead.
+// Registers are sorted by address; a gap between two registers' address
+// ranges starts a new batch.
+func BuildBatches(registers []Register, maxRegistersPerRead uint16) []Batch {
+	if maxRegistersPerRead == 0 {
+		maxRegistersPerRead = 125
+	}
+
+	sorted := make([]Register, len(registers))
+	copy(sorted, registers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	var batches []Batch
+	for _, reg := range sorted {
+		regEnd := reg.Address + reg.wordLength()
+
+		if len(batches) > 0 {
+			last := &batches[len(batches)-1]
+			batchEnd := last.StartAddress + last.Quantity
+			newEnd := regEnd
+			if batchEnd > newEnd {
+				newEnd = batchEnd
+			}
+			if reg.Address <= batchEnd && newEnd-last.StartAddress <= maxRegistersPerRead {
+				last.Quantity = newEnd - last.StartAddress
+				last.Registers = append(last.Registers, reg)
+				continue
+			}
+		}
+
+		batches = append(batches, Batch{
+			StartAddress: reg.Address,
+			Quantity:     regEnd - reg.Address,
+			Registers:    []Register{reg},
+		})
+	}
+
+	return batches
+}
+
+// Decode extracts reg's value out of words, where words is the full set
+// of registers returned for the batch reg belongs to and batchStart is
+// that batch's starting address.
+func Decode(reg Register, batchStart uint16, words []uint16) (interface{}, error) {
+	offset := int(reg.Address - batchStart)
+	length := int(reg.wordLength())
+	if offset < 0 || offset+length > len(words) {
+		return nil, fmt.Errorf("registermap: register %q (addr %d) out of range of read batch", reg.Name, reg.Address)
+	}
+	regWords := words[offset : offset+length]
+
+	switch reg.Type {
+	case TypeWord:
+		return reg.apply(float64(regWords[0])), nil
+
+	case TypeSWord:
+		return reg.apply(float64(int16(regWords[0]))), nil
+
+	case TypeUint32:
+		raw := uint32(regWords[0])<<16 | uint32(regWords[1])
+		return reg.apply(float64(raw)), nil
+
+	case TypeFloat32ABCD:
+		raw := uint32(regWords[0])<<16 | uint32(regWords[1])
+		return reg.apply(float64(math.Float32frombits(raw))), nil
+
+	case TypeFloat32CDAB:
+		raw := uint32(regWords[1])<<16 | uint32(regWords[0])
+		return reg.apply(float64(math.Float32frombits(raw))), nil
+
+	case TypeBit:
+		raw := regWords[0]
+		if reg.BitMask != 0 {
+			raw &= reg.BitMask
+		}
+		raw >>= reg.BitShift
+		return reg.apply(float64(raw)), nil
+
+	default:
+		return nil, fmt.Errorf("registermap: unknown register type %q for %q", reg.Type, reg.Name)
+	}
+}
+
+// apply scales the decoded raw value and, if an enum label matches,
+// returns that label instead of the number.
+func (r Register) apply(raw float64) interface{} {
+	value := raw*r.scale() + r.Offset
+
+	if len(r.Enum) > 0 {
+		key := fmt.Sprintf("%d", int64(value))
+		if label, ok := r.Enum[key]; ok {
+			return label
+		}
+	}
+
+	if r.Scale == 0 && r.Offset == 0 {
+		return int64(value)
+	}
+	return value
+}
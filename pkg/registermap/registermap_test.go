@@ -0,0 +1,187 @@
+package registermap
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecodeWord(t *testing.T) {
+	reg := Register{Name: "r", Address: 10, Type: TypeWord}
+	got, err := Decode(reg, 10, []uint16{42})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != int64(42) {
+		t.Errorf("Decode(WORD) = %v, want int64(42)", got)
+	}
+}
+
+func TestDecodeSWord(t *testing.T) {
+	reg := Register{Name: "r", Address: 10, Type: TypeSWord}
+	got, err := Decode(reg, 10, []uint16{0xFFFF}) // -1 as int16
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != int64(-1) {
+		t.Errorf("Decode(SWORD) = %v, want int64(-1)", got)
+	}
+}
+
+func TestDecodeUint32(t *testing.T) {
+	reg := Register{Name: "r", Address: 10, Type: TypeUint32}
+	got, err := Decode(reg, 10, []uint16{0x0001, 0x0002}) // 0x00010002
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != int64(0x00010002) {
+		t.Errorf("Decode(UINT32) = %v, want int64(%d)", got, 0x00010002)
+	}
+}
+
+func TestDecodeFloat32ABCD(t *testing.T) {
+	bits := math.Float32bits(3.25)
+	hi := uint16(bits >> 16)
+	lo := uint16(bits & 0xFFFF)
+
+	// Scale must be explicitly set: apply() only keeps the fractional part
+	// when Scale or Offset is non-zero, truncating to int64 otherwise.
+	reg := Register{Name: "r", Address: 10, Type: TypeFloat32ABCD, Scale: 1}
+	got, err := Decode(reg, 10, []uint16{hi, lo})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != float64(3.25) {
+		t.Errorf("Decode(FLOAT32_ABCD) = %v, want 3.25", got)
+	}
+}
+
+func TestDecodeFloat32CDAB(t *testing.T) {
+	bits := math.Float32bits(3.25)
+	hi := uint16(bits >> 16)
+	lo := uint16(bits & 0xFFFF)
+
+	reg := Register{Name: "r", Address: 10, Type: TypeFloat32CDAB, Scale: 1}
+	// Word order swapped relative to ABCD.
+	got, err := Decode(reg, 10, []uint16{lo, hi})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != float64(3.25) {
+		t.Errorf("Decode(FLOAT32_CDAB) = %v, want 3.25", got)
+	}
+}
+
+func TestDecodeBit(t *testing.T) {
+	reg := Register{Name: "r", Address: 10, Type: TypeBit, BitMask: 0x0F00, BitShift: 8}
+	got, err := Decode(reg, 10, []uint16{0x0300})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != int64(3) {
+		t.Errorf("Decode(BIT) = %v, want int64(3)", got)
+	}
+}
+
+func TestDecodeScaleAndEnum(t *testing.T) {
+	reg := Register{
+		Name:    "r",
+		Address: 10,
+		Type:    TypeWord,
+		Scale:   0.1,
+		Enum:    map[string]string{"4": "running"},
+	}
+	got, err := Decode(reg, 10, []uint16{40})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != "running" {
+		t.Errorf("Decode with matching enum = %v, want %q", got, "running")
+	}
+}
+
+func TestDecodeOutOfRange(t *testing.T) {
+	reg := Register{Name: "r", Address: 12, Type: TypeUint32}
+	if _, err := Decode(reg, 10, []uint16{1}); err == nil {
+		t.Error("Decode with register outside the batch's words should fail")
+	}
+}
+
+func TestDecodeUnknownType(t *testing.T) {
+	reg := Register{Name: "r", Address: 10, Type: "BOGUS"}
+	if _, err := Decode(reg, 10, []uint16{1}); err == nil {
+		t.Error("Decode with an unknown type should fail")
+	}
+}
+
+func TestBuildBatchesAdjacentRegistersMerge(t *testing.T) {
+	registers := []Register{
+		{Name: "a", Address: 0, Type: TypeWord},
+		{Name: "b", Address: 1, Type: TypeWord},
+	}
+	batches := BuildBatches(registers, 125)
+	if len(batches) != 1 {
+		t.Fatalf("len(batches) = %d, want 1", len(batches))
+	}
+	if batches[0].StartAddress != 0 || batches[0].Quantity != 2 {
+		t.Errorf("batch = %+v, want start 0 quantity 2", batches[0])
+	}
+}
+
+func TestBuildBatchesOverlappingRegistersMerge(t *testing.T) {
+	registers := []Register{
+		{Name: "a", Address: 0, Type: TypeUint32}, // covers 0-1
+		{Name: "b", Address: 1, Type: TypeWord},   // overlaps at 1
+	}
+	batches := BuildBatches(registers, 125)
+	if len(batches) != 1 {
+		t.Fatalf("len(batches) = %d, want 1", len(batches))
+	}
+	if batches[0].StartAddress != 0 || batches[0].Quantity != 2 {
+		t.Errorf("batch = %+v, want start 0 quantity 2", batches[0])
+	}
+}
+
+func TestBuildBatchesGapStartsNewBatch(t *testing.T) {
+	registers := []Register{
+		{Name: "a", Address: 0, Type: TypeWord},
+		{Name: "b", Address: 5, Type: TypeWord},
+	}
+	batches := BuildBatches(registers, 125)
+	if len(batches) != 2 {
+		t.Fatalf("len(batches) = %d, want 2", len(batches))
+	}
+	if batches[1].StartAddress != 5 || batches[1].Quantity != 1 {
+		t.Errorf("second batch = %+v, want start 5 quantity 1", batches[1])
+	}
+}
+
+func TestBuildBatchesMaxRegistersPerReadBoundary(t *testing.T) {
+	registers := []Register{
+		{Name: "a", Address: 0, Type: TypeWord},
+		{Name: "b", Address: 1, Type: TypeWord},
+		{Name: "c", Address: 2, Type: TypeWord},
+	}
+
+	// Exactly reaches the cap: stays in one batch.
+	batches := BuildBatches(registers, 3)
+	if len(batches) != 1 {
+		t.Fatalf("cap exactly reached: len(batches) = %d, want 1", len(batches))
+	}
+
+	// One more register would exceed the cap: splits into two batches.
+	batches = BuildBatches(registers, 2)
+	if len(batches) != 2 {
+		t.Fatalf("cap exceeded: len(batches) = %d, want 2", len(batches))
+	}
+	if batches[0].Quantity != 2 || batches[1].Quantity != 1 {
+		t.Errorf("batches = %+v, want quantities [2 1]", batches)
+	}
+}
+
+func TestBuildBatchesDefaultMax(t *testing.T) {
+	registers := []Register{{Name: "a", Address: 0, Type: TypeWord}}
+	batches := BuildBatches(registers, 0)
+	if len(batches) != 1 {
+		t.Fatalf("len(batches) = %d, want 1", len(batches))
+	}
+}